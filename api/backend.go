@@ -0,0 +1,155 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"gitlab.com/pions/pion/util/go/jwt"
+	"gitlab.com/pions/pion/util/go/log"
+
+	pionRoom "gitlab.com/pions/pion/signaler/room"
+
+	"github.com/gorilla/websocket"
+)
+
+// minBackendRandomBytes is the minimum length required of the
+// Spreed-Signaling-Random header, per the backend signing scheme.
+const minBackendRandomBytes = 32
+
+type backendEnvelope struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type messageBackendEvent struct {
+	messageBase
+	Args json.RawMessage `json:"args"`
+}
+
+// HandleBackendRoom lets a trusted application server push events
+// ("message", "disinvite", "update") into a room without holding a
+// websocket of its own, modelled after the Nextcloud/Spreed backend
+// signaling protocol. Requests are authenticated via a
+// Spreed-Signaling-Random/Spreed-Signaling-Checksum header pair rather
+// than a JWT.
+func HandleBackendRoom(w http.ResponseWriter, r *http.Request) {
+	apiKeyID, room, ok := parseBackendRoomPath(r.URL.Path)
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	random := r.Header.Get("Spreed-Signaling-Random")
+	if len(random) < minBackendRandomBytes {
+		http.Error(w, "missing or too short Spreed-Signaling-Random", http.StatusForbidden)
+		return
+	}
+
+	secret, err := jwt.GetSharedSecret(apiKeyID)
+	if err != nil {
+		log.Error().Err(err).Str("ApiKeyID", apiKeyID).Msg("Unknown backend apiKeyID")
+		http.Error(w, "unknown apiKeyID", http.StatusForbidden)
+		return
+	}
+
+	if !validBackendChecksum(random, body, secret, r.Header.Get("Spreed-Signaling-Checksum")) {
+		http.Error(w, "invalid checksum", http.StatusForbidden)
+		return
+	}
+
+	envelope := backendEnvelope{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		http.Error(w, "malformed body", http.StatusBadRequest)
+		return
+	}
+
+	switch envelope.Type {
+	case "disinvite":
+		handleBackendDisinvite(apiKeyID, room, envelope.Payload)
+	case "message", "update":
+		announceBackendEvent(apiKeyID, room, envelope.Type, envelope.Payload)
+	default:
+		http.Error(w, "unknown type", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseBackendRoomPath(path string) (apiKeyID, room string, ok bool) {
+	const prefix = "/backend/room/"
+	if !strings.HasPrefix(path, prefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(path, prefix), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func validBackendChecksum(random string, body []byte, secret, checksum string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(random))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(checksum))
+}
+
+// announceBackendEvent fans payload out to apiKeyID/room's members the same
+// way announceExit does, tagging the message with eventType as its method.
+func announceBackendEvent(apiKeyID, room, eventType string, payload json.RawMessage) {
+	message := messageBackendEvent{messageBase: messageBase{Method: eventType}}
+	message.Args = payload
+
+	if membersMap, ok := pionRoom.GetRoom(apiKeyID, room); ok == true {
+		membersMap.Range(func(key, value interface{}) bool {
+			if err := value.(*pionSession).WriteJSON(message); err != nil {
+				log.Error().Err(err).Str("SessionKey", key.(string)).Msg("Failed to deliver backend event")
+			}
+			return true
+		})
+	}
+}
+
+func handleBackendDisinvite(apiKeyID, room string, payload json.RawMessage) {
+	target := struct {
+		SessionKey string `json:"sessionKey"`
+	}{}
+	if err := json.Unmarshal(payload, &target); err != nil {
+		log.Error().Err(err).Msg("Malformed disinvite payload")
+		return
+	}
+
+	session, ok := pionRoom.GetSession(apiKeyID, room, target.SessionKey)
+	if !ok {
+		return
+	}
+	victim := session.(*pionSession)
+	if err := victim.WriteClose(websocket.FormatCloseMessage(websocket.CloseNormalClosure, "disinvited")); err != nil {
+		log.Error().Err(err).Msg("Failed to write close frame for disinvite")
+	}
+	// Force the connection down instead of relying on the client to act on
+	// the close frame, so a non-cooperating client doesn't leak its read
+	// goroutine and socket. This marks the session moderated, which
+	// suppresses HandleRootWSUpgrade's own announceExit, so we announce the
+	// departure ourselves below.
+	if err := victim.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to force-close disinvited session")
+	}
+	if err := pionRoom.DestroySession(apiKeyID, room, target.SessionKey); err != nil {
+		log.Error().Err(err).Msg("Failed to destroy session for disinvite")
+	}
+	announceExit(apiKeyID, room, target.SessionKey)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestValidBackendChecksum(t *testing.T) {
+	const secret = "shared-secret"
+	const random = "0123456789abcdef0123456789abcdef"
+	body := []byte(`{"type":"message","payload":{}}`)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(random))
+	mac.Write(body)
+	checksum := hex.EncodeToString(mac.Sum(nil))
+
+	if !validBackendChecksum(random, body, secret, checksum) {
+		t.Fatal("expected a correctly computed checksum to validate")
+	}
+	if validBackendChecksum(random, body, secret, checksum+"00") {
+		t.Fatal("expected a tampered checksum to be rejected")
+	}
+	if validBackendChecksum(random, []byte(`{"type":"tampered"}`), secret, checksum) {
+		t.Fatal("expected a checksum to be rejected when the body changes")
+	}
+}
+
+func TestParseBackendRoomPath(t *testing.T) {
+	cases := []struct {
+		path     string
+		apiKeyID string
+		room     string
+		wantOK   bool
+	}{
+		{path: "/backend/room/key1/room1", apiKeyID: "key1", room: "room1", wantOK: true},
+		{path: "/backend/room/key1/room1/extra", apiKeyID: "key1", room: "room1/extra", wantOK: true},
+		{path: "/backend/room/key1/", wantOK: false},
+		{path: "/backend/room/key1", wantOK: false},
+		{path: "/not/backend/room/key1/room1", wantOK: false},
+	}
+
+	for _, c := range cases {
+		apiKeyID, room, ok := parseBackendRoomPath(c.path)
+		if ok != c.wantOK {
+			t.Errorf("parseBackendRoomPath(%q) ok = %v, want %v", c.path, ok, c.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if apiKeyID != c.apiKeyID || room != c.room {
+			t.Errorf("parseBackendRoomPath(%q) = (%q, %q), want (%q, %q)", c.path, apiKeyID, room, c.apiKeyID, c.room)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gitlab.com/pions/pion/util/go/log"
+
+	pionRoom "gitlab.com/pions/pion/signaler/room"
+)
+
+// maxUserMessageBytes bounds how large a single "usermessage" payload may
+// be before it is rejected outright.
+const maxUserMessageBytes = 16 * 1024
+
+// defaultUserMessageRate is the number of "usermessage" messages a session
+// may send per second before it is disconnected.
+const defaultUserMessageRate = 5.0
+
+type messageUserMessage struct {
+	messageBase
+	Args struct {
+		Dst   string          `json:"dst,omitempty"`
+		Kind  string          `json:"kind"`
+		Value json.RawMessage `json:"value"`
+		Src   string          `json:"src,omitempty"`
+	} `json:"args"`
+}
+
+// sendUserMessage routes an application-defined "usermessage" to a single
+// peer (Dst set) or fans it out to the rest of the room (Dst empty), the
+// same way sendSdp and announceExit do respectively.
+func sendUserMessage(session *pionSession, raw []byte) error {
+	if len(raw) > maxUserMessageBytes {
+		return userError("usermessage exceeds maximum size")
+	}
+	if !session.userMessageLimiter.Allow() {
+		return userError("usermessage rate limit exceeded")
+	}
+
+	message := messageUserMessage{}
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return protocolError(fmt.Sprintf("malformed usermessage: %v", err))
+	}
+	message.Args.Src = session.claims.SessionKey
+
+	if message.Args.Dst == "" {
+		if membersMap, ok := pionRoom.GetRoom(session.claims.ApiKeyID, session.claims.Room); ok == true {
+			membersMap.Range(func(key, value interface{}) bool {
+				if key.(string) == session.claims.SessionKey {
+					return true
+				}
+				if err := value.(*pionSession).WriteJSON(message); err != nil {
+					log.Error().Err(err).Str("SessionKey", session.claims.SessionKey).Msg("Failed to fan out usermessage")
+				}
+				return true
+			})
+		}
+		return nil
+	}
+
+	dstConn, ok := pionRoom.GetSession(session.claims.ApiKeyID, session.claims.Room, message.Args.Dst)
+	if ok == false {
+		return userError("no entry found in membersMap")
+	}
+	return dstConn.(*pionSession).WriteJSON(message)
+}
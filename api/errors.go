@@ -0,0 +1,78 @@
+package api
+
+import (
+	"time"
+
+	"gitlab.com/pions/pion/util/go/log"
+
+	"github.com/pkg/errors"
+
+	"github.com/gorilla/websocket"
+)
+
+// protocolError indicates the client violated the signaling wire protocol
+// itself (malformed JSON, an unknown method) rather than making a request
+// the server legitimately refused.
+type protocolError string
+
+func (e protocolError) Error() string { return string(e) }
+
+// userError indicates a well-formed request that the server refused for a
+// reason the client should be told about in plain language (missing
+// permission, rate limit, banned, ...).
+type userError string
+
+func (e userError) Error() string { return string(e) }
+
+// authError indicates the client failed to authenticate (missing, invalid,
+// or rejected JWT). It gets its own close code, distinct from
+// protocolError, so a client can tell "you're unauthorized" apart from
+// "you sent garbage".
+type authError string
+
+func (e authError) Error() string { return string(e) }
+
+// closeCodeAuthFailed is a close code in the 4000-4999 private-use range
+// (reserved for application use by RFC 6455) sent when authError closes a
+// connection.
+const closeCodeAuthFailed = 4401
+
+// errorToWSCloseMessage maps err to the websocket close code and payload
+// that should be sent to a client before its connection is dropped.
+// errors.Cause is used so errors wrapped by errors.Wrap still match.
+func errorToWSCloseMessage(err error) (code int, payload []byte) {
+	switch e := errors.Cause(err).(type) {
+	case protocolError:
+		code = websocket.CloseProtocolError
+		payload = websocket.FormatCloseMessage(code, string(e))
+	case userError:
+		code = websocket.CloseNormalClosure
+		payload = websocket.FormatCloseMessage(code, string(e))
+	case authError:
+		code = closeCodeAuthFailed
+		payload = websocket.FormatCloseMessage(code, string(e))
+	default:
+		code = websocket.CloseInternalServerErr
+		payload = websocket.FormatCloseMessage(code, "internal error")
+	}
+	return code, payload
+}
+
+// closeWithError sends session a close frame describing err before the
+// caller drops the connection.
+func closeWithError(session *pionSession, err error) {
+	code, payload := errorToWSCloseMessage(err)
+	if writeErr := session.WriteClose(payload); writeErr != nil {
+		log.Error().Err(writeErr).Int("code", code).Msg("Failed to write close frame")
+	}
+}
+
+// closeConnWithError is the pre-session-creation equivalent of
+// closeWithError, used while upgrading a connection before its pionSession
+// exists.
+func closeConnWithError(c *websocket.Conn, err error) {
+	code, payload := errorToWSCloseMessage(err)
+	if writeErr := c.WriteControl(websocket.CloseMessage, payload, time.Now().Add(writeWait)); writeErr != nil {
+		log.Error().Err(writeErr).Int("code", code).Msg("Failed to write close frame")
+	}
+}
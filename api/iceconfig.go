@@ -0,0 +1,151 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gitlab.com/pions/pion/util/go/log"
+)
+
+// iceServerConfig mirrors webrtc.Configuration's ICEServer shape so it can
+// be sent to clients without this package depending on pion/webrtc.
+type iceServerConfig struct {
+	URLs           []string `json:"urls"`
+	Username       string   `json:"username,omitempty"`
+	Credential     string   `json:"credential,omitempty"`
+	CredentialType string   `json:"credentialType,omitempty"`
+}
+
+type iceConfiguration struct {
+	ICEServers         []iceServerConfig `json:"iceServers"`
+	ICETransportPolicy string            `json:"iceTransportPolicy,omitempty"`
+}
+
+// iceConfigEnvVar names the env var holding the path to the base ICE/TURN
+// configuration file, reloaded on SIGHUP so operators can rotate TURN
+// secrets without restarting the process.
+const iceConfigEnvVar = "ICE_CONFIG_PATH"
+
+// ephemeralTURNCredentialTTL is how long a session's derived TURN
+// credential remains valid for.
+const ephemeralTURNCredentialTTL = 24 * time.Hour
+
+var currentICEConfig atomic.Value // holds *iceConfiguration
+
+func init() {
+	currentICEConfig.Store(&iceConfiguration{})
+
+	path := os.Getenv(iceConfigEnvVar)
+	if path == "" {
+		return
+	}
+
+	loadICEConfig(path)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			loadICEConfig(path)
+		}
+	}()
+}
+
+func loadICEConfig(path string) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to read ICE config")
+		return
+	}
+
+	config := &iceConfiguration{}
+	if err := json.Unmarshal(raw, config); err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to parse ICE config")
+		return
+	}
+
+	currentICEConfig.Store(config)
+	log.Info().Str("path", path).Msg("Loaded ICE config")
+}
+
+// ephemeralTURNCredential derives short-lived TURN credentials for
+// sessionKey using the standard ephemeral TURN REST API scheme: the
+// username is "<expiry unix seconds>:sessionKey" and the credential is the
+// base64-encoded HMAC-SHA1 of that username keyed on secret.
+func ephemeralTURNCredential(sessionKey, secret string, ttl time.Duration) (username, credential string) {
+	username = fmt.Sprintf("%d:%s", time.Now().Add(ttl).Unix(), sessionKey)
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username))
+	credential = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return username, credential
+}
+
+// isTURNURL reports whether u uses the turn: or turns: scheme, as opposed
+// to a STUN-only entry.
+func isTURNURL(u string) bool {
+	scheme := u
+	if i := strings.Index(u, ":"); i >= 0 {
+		scheme = u[:i]
+	}
+	switch strings.ToLower(scheme) {
+	case "turn", "turns":
+		return true
+	default:
+		return false
+	}
+}
+
+// hasTURNURL reports whether urls contains at least one turn:/turns: entry.
+func hasTURNURL(urls []string) bool {
+	for _, u := range urls {
+		if isTURNURL(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// iceConfigForSession returns the effective ICE configuration for session,
+// layering any per-API-key override carried in its claims over the base
+// configuration and stamping TURN entries with ephemeral credentials when
+// the claims carry a TURN secret.
+func iceConfigForSession(session *pionSession) iceConfiguration {
+	base := currentICEConfig.Load().(*iceConfiguration)
+	config := iceConfiguration{
+		ICEServers:         append(make([]iceServerConfig, 0, len(base.ICEServers)), base.ICEServers...),
+		ICETransportPolicy: base.ICETransportPolicy,
+	}
+
+	if len(session.claims.ICEConfigOverride) > 0 {
+		if err := json.Unmarshal(session.claims.ICEConfigOverride, &config); err != nil {
+			log.Error().Err(err).Msg("Failed to apply per-API-key ICE override")
+		}
+	}
+	if config.ICEServers == nil {
+		config.ICEServers = make([]iceServerConfig, 0)
+	}
+
+	if session.claims.TURNSecret != "" {
+		username, credential := ephemeralTURNCredential(session.claims.SessionKey, session.claims.TURNSecret, ephemeralTURNCredentialTTL)
+		for i := range config.ICEServers {
+			if !hasTURNURL(config.ICEServers[i].URLs) {
+				continue
+			}
+			config.ICEServers[i].Username = username
+			config.ICEServers[i].Credential = credential
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEphemeralTURNCredential(t *testing.T) {
+	const sessionKey, secret = "sess1", "turn-secret"
+	ttl := time.Hour
+
+	username, credential := ephemeralTURNCredential(sessionKey, secret, ttl)
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username))
+	wantCredential := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if credential != wantCredential {
+		t.Fatalf("credential = %q, want %q", credential, wantCredential)
+	}
+
+	var expiry int64
+	if _, err := fmt.Sscanf(username, "%d:"+sessionKey, &expiry); err != nil {
+		t.Fatalf("username %q did not match expected \"<expiry>:%s\" shape: %v", username, sessionKey, err)
+	}
+	wantExpiry := time.Now().Add(ttl).Unix()
+	if diff := wantExpiry - expiry; diff < -1 || diff > 1 {
+		t.Errorf("embedded expiry %d not within 1s of expected %d", expiry, wantExpiry)
+	}
+}
+
+func TestHasTURNURL(t *testing.T) {
+	cases := []struct {
+		urls []string
+		want bool
+	}{
+		{urls: []string{"stun:stun.example.com:3478"}, want: false},
+		{urls: []string{"turn:turn.example.com:3478"}, want: true},
+		{urls: []string{"turns:turn.example.com:5349"}, want: true},
+		{urls: []string{"TURN:turn.example.com:3478"}, want: true},
+		{urls: []string{"stun:stun.example.com", "turn:turn.example.com"}, want: true},
+		{urls: nil, want: false},
+	}
+
+	for _, c := range cases {
+		if got := hasTURNURL(c.urls); got != c.want {
+			t.Errorf("hasTURNURL(%v) = %v, want %v", c.urls, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+// messageBase is embedded in every signaling message and carries the
+// discriminator used to dispatch in handleClientMessage.
+type messageBase struct {
+	Method string `json:"method"`
+}
+
+type messageMembers struct {
+	messageBase
+	Args struct {
+		Members []string `json:"members"`
+	} `json:"args"`
+}
+
+type messageSDP struct {
+	messageBase
+	Args struct {
+		Src string `json:"src"`
+		Dst string `json:"dst"`
+		Sdp string `json:"sdp"`
+	} `json:"args"`
+}
+
+type messageCandidate struct {
+	messageBase
+	Args struct {
+		Src       string `json:"src"`
+		Dst       string `json:"dst"`
+		Candidate string `json:"candidate"`
+	} `json:"args"`
+}
+
+type messagePing struct {
+	messageBase
+}
+
+type messageICEServers struct {
+	messageBase
+	Args iceConfiguration `json:"args"`
+}
+
+type messageExit struct {
+	messageBase
+	Args struct {
+		SessionKey string `json:"sessionKey"`
+	} `json:"args"`
+}
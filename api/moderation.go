@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gitlab.com/pions/pion/util/go/jwt"
+	"gitlab.com/pions/pion/util/go/log"
+
+	pionRoom "gitlab.com/pions/pion/signaler/room"
+)
+
+// permissionOp is the jwt.PionClaim permission required to moderate a room.
+const permissionOp = "op"
+
+func hasPermission(claims *jwt.PionClaim, permission string) bool {
+	for _, p := range claims.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type messageSessionTarget struct {
+	messageBase
+	Args struct {
+		SessionKey string `json:"sessionKey"`
+	} `json:"args"`
+}
+
+type messageModeration struct {
+	messageBase
+	Args struct {
+		SessionKey string `json:"sessionKey"`
+		By         string `json:"by"`
+	} `json:"args"`
+}
+
+// announceModeration tells the remaining members of apiKey/room that
+// sessionKey was kicked or banned by by, the same way announceExit does.
+func announceModeration(apiKey, room, method, sessionKey, by string) {
+	message := messageModeration{messageBase: messageBase{Method: method}}
+	message.Args.SessionKey = sessionKey
+	message.Args.By = by
+
+	if membersMap, ok := pionRoom.GetRoom(apiKey, room); ok == true {
+		membersMap.Range(func(key, value interface{}) bool {
+			if err := value.(*pionSession).WriteJSON(message); err != nil {
+				log.Error().Err(err).Str("SessionKey", sessionKey).Msg("Failed to announce moderation event")
+			}
+			return true
+		})
+	}
+}
+
+func handleLock(session *pionSession, locked bool) error {
+	if !hasPermission(session.claims, permissionOp) {
+		return userError("only a room operator can lock or unlock a room")
+	}
+	pionRoom.SetLocked(session.claims.ApiKeyID, session.claims.Room, locked)
+	return nil
+}
+
+func handleKick(session *pionSession, raw []byte) error {
+	if !hasPermission(session.claims, permissionOp) {
+		return userError("only a room operator can kick a participant")
+	}
+	message := messageSessionTarget{}
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return protocolError(fmt.Sprintf("malformed kick message: %v", err))
+	}
+	if _, ok := pionRoom.GetSession(session.claims.ApiKeyID, session.claims.Room, message.Args.SessionKey); !ok {
+		return userError("no such participant in this room")
+	}
+	if err := pionRoom.Kick(session.claims.ApiKeyID, session.claims.Room, message.Args.SessionKey, session.claims.SessionKey); err != nil {
+		return err
+	}
+	announceModeration(session.claims.ApiKeyID, session.claims.Room, "kick", message.Args.SessionKey, session.claims.SessionKey)
+	return nil
+}
+
+func handleBan(session *pionSession, raw []byte) error {
+	if !hasPermission(session.claims, permissionOp) {
+		return userError("only a room operator can ban a participant")
+	}
+	message := messageSessionTarget{}
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return protocolError(fmt.Sprintf("malformed ban message: %v", err))
+	}
+	target, ok := pionRoom.GetSession(session.claims.ApiKeyID, session.claims.Room, message.Args.SessionKey)
+	if ok == false {
+		return userError("no such participant in this room")
+	}
+	remoteAddr := target.(*pionSession).websocket.RemoteAddr().String()
+	if err := pionRoom.Ban(session.claims.ApiKeyID, session.claims.Room, message.Args.SessionKey, remoteAddr, session.claims.SessionKey); err != nil {
+		return err
+	}
+	announceModeration(session.claims.ApiKeyID, session.claims.Room, "ban", message.Args.SessionKey, session.claims.SessionKey)
+	return nil
+}
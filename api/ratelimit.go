@@ -0,0 +1,39 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a simple token bucket, refilled continuously at
+// ratePerSecond up to a burst of one second's worth of tokens.
+type rateLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	rate   float64
+	last   time.Time
+}
+
+func newRateLimiter(ratePerSecond float64) *rateLimiter {
+	return &rateLimiter{tokens: ratePerSecond, rate: ratePerSecond, last: time.Now()}
+}
+
+// Allow reports whether a single unit of work may proceed now, consuming a
+// token if so.
+func (r *rateLimiter) Allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.rate {
+		r.tokens = r.rate
+	}
+	r.last = now
+
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllowsBurstThenBlocks(t *testing.T) {
+	limiter := newRateLimiter(10)
+
+	for i := 0; i < 10; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("expected token %d of initial burst to be allowed", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Fatal("expected burst to be exhausted after consuming rate-many tokens")
+	}
+}
+
+func TestRateLimiterRefillsOverTime(t *testing.T) {
+	limiter := newRateLimiter(100)
+
+	for limiter.Allow() {
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Fatal("expected tokens to have refilled after waiting")
+	}
+}
+
+func TestRateLimiterRefillDoesNotExceedBurst(t *testing.T) {
+	limiter := newRateLimiter(5)
+	limiter.last = time.Now().Add(-time.Hour)
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if limiter.Allow() {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Fatalf("expected refill to be capped at the burst of 5 tokens, got %d allowed", allowed)
+	}
+}
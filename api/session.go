@@ -0,0 +1,66 @@
+package api
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/pions/pion/util/go/jwt"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeWait bounds how long a control frame write (ping, close) may block.
+const writeWait = 5 * time.Second
+
+// pionSession wraps a single client's websocket connection together with
+// the claims it authenticated with. WriteJSON serializes writes since the
+// gorilla/websocket connection is not safe for concurrent writers.
+type pionSession struct {
+	mu                 sync.Mutex
+	websocket          *websocket.Conn
+	claims             *jwt.PionClaim
+	lastPong           atomic.Int64 // unix seconds of the last pong seen from this peer
+	userMessageLimiter *rateLimiter
+	moderated          atomic.Bool // set once Close is called as part of a kick/ban/disinvite
+}
+
+// touchPong records that a pong (native frame or "pong" message) was just
+// seen from this session.
+func (s *pionSession) touchPong() {
+	s.lastPong.Store(time.Now().Unix())
+}
+
+// pongAge reports how long it has been since touchPong was last called.
+func (s *pionSession) pongAge() time.Duration {
+	return time.Since(time.Unix(s.lastPong.Load(), 0))
+}
+
+// WriteJSON writes v to the underlying websocket, guarding against
+// concurrent writers from different goroutines (ping ticker vs read loop).
+func (s *pionSession) WriteJSON(v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.websocket.WriteJSON(v)
+}
+
+// WriteClose writes a close control frame carrying payload (produced by
+// websocket.FormatCloseMessage), satisfying room.Moderated so Kick/Ban can
+// terminate this session.
+func (s *pionSession) WriteClose(payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.websocket.WriteControl(websocket.CloseMessage, payload, time.Now().Add(writeWait))
+}
+
+// Close force-closes the underlying connection instead of relying on the
+// client to act on a close frame, satisfying room.Moderated. It marks the
+// session as moderated so HandleRootWSUpgrade's own cleanup doesn't
+// announce a second, redundant "exit" once the forced close unblocks the
+// read loop.
+func (s *pionSession) Close() error {
+	s.moderated.Store(true)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.websocket.Close()
+}
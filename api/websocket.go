@@ -18,6 +18,10 @@ import (
 
 const pingPeriod = 5 * time.Second
 
+// pongWait is how long a read may stay idle before the connection is
+// considered dead; it is refreshed on every read and on every pong.
+const pongWait = 15 * time.Second
+
 func sendMembers(session *pionSession) error {
 	message := messageMembers{messageBase: messageBase{Method: "members"}}
 	message.Args.Members = make([]string, 0)
@@ -61,6 +65,12 @@ func sendCandidate(session *pionSession, raw []byte) error {
 	return dstConn.(*pionSession).WriteJSON(message)
 }
 
+func sendICEServers(session *pionSession) error {
+	message := messageICEServers{messageBase: messageBase{Method: "iceServers"}}
+	message.Args = iceConfigForSession(session)
+	return session.WriteJSON(message)
+}
+
 func sendPing(session *pionSession) error {
 	message := messagePing{messageBase: messageBase{Method: "ping"}}
 	return session.WriteJSON(message)
@@ -89,7 +99,7 @@ var upgrader = websocket.Upgrader{
 func handleClientMessage(session *pionSession, raw []byte) error {
 	message := messageBase{}
 	if err := json.Unmarshal(raw, &message); err != nil {
-		return err
+		return protocolError(fmt.Sprintf("malformed message: %v", err))
 	}
 
 	switch message.Method {
@@ -99,10 +109,23 @@ func handleClientMessage(session *pionSession, raw []byte) error {
 		return errors.Wrap(sendSdp(session, raw), "sendSdp failed")
 	case "candidate":
 		return errors.Wrap(sendCandidate(session, raw), "sendCandidate failed")
+	case "iceServers":
+		return errors.Wrap(sendICEServers(session), "sendICEServers failed")
+	case "lock":
+		return errors.Wrap(handleLock(session, true), "handleLock failed")
+	case "unlock":
+		return errors.Wrap(handleLock(session, false), "handleLock failed")
+	case "kick":
+		return errors.Wrap(handleKick(session, raw), "handleKick failed")
+	case "ban":
+		return errors.Wrap(handleBan(session, raw), "handleBan failed")
+	case "usermessage":
+		return errors.Wrap(sendUserMessage(session, raw), "sendUserMessage failed")
 	case "pong":
+		session.touchPong()
 		return nil
 	default:
-		return fmt.Errorf("unknown client method %s", message.Method)
+		return protocolError(fmt.Sprintf("unknown client method %s", message.Method))
 	}
 }
 
@@ -111,14 +134,23 @@ func handleWS(session *pionSession) {
 	in := make(chan []byte)
 	pingTicker := time.NewTicker(pingPeriod)
 
+	session.websocket.SetReadDeadline(time.Now().Add(pongWait))
+	session.websocket.SetPongHandler(func(string) error {
+		session.touchPong()
+		return session.websocket.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	go func() {
 		for {
 			_, raw, err := session.websocket.ReadMessage()
 			if err != nil {
-				log.Warn().Err(err).Msg("websocket.ReadMessage error")
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					log.Warn().Err(err).Msg("websocket.ReadMessage error")
+				}
 				close(stop)
 				break
 			}
+			session.websocket.SetReadDeadline(time.Now().Add(pongWait))
 			in <- raw
 		}
 		log.Info().Str("RemoteAddr", session.websocket.RemoteAddr().String()).Msg("HandleWS ending")
@@ -127,8 +159,17 @@ func handleWS(session *pionSession) {
 	for {
 		select {
 		case _ = <-pingTicker.C:
+			if session.pongAge() > 2*pingPeriod {
+				log.Warn().
+					Str("SessionKey", session.claims.SessionKey).
+					Dur("pongAge", session.pongAge()).
+					Msg("Peer missed heartbeat, closing dead connection")
+				closeWithError(session, userError("no pong received, closing idle connection"))
+				return
+			}
 			if err := sendPing(session); err != nil {
 				log.Error().Err(err).Msg("sendPing has failed")
+				closeWithError(session, err)
 				return
 			}
 		case raw := <-in:
@@ -140,6 +181,7 @@ func handleWS(session *pionSession) {
 				Msg("Reading from Websocket")
 			if err := handleClientMessage(session, raw); err != nil {
 				log.Error().Err(err).Msg("handleClientMessage has failed")
+				closeWithError(session, err)
 				return
 			}
 		case <-stop:
@@ -169,19 +211,28 @@ func HandleRootWSUpgrade(w http.ResponseWriter, r *http.Request) {
 
 	authTokens := r.URL.Query()["authToken"]
 	if len(authTokens) != 1 {
-		fmt.Println("Bad authToken count, should be 1", len(authTokens))
+		closeConnWithError(c, authError(fmt.Sprintf("expected exactly one authToken, got %d", len(authTokens))))
 		return
 	}
 	claims, err := jwt.GetClaims(authTokens[0])
 	if err != nil {
-		fmt.Println("Failed to getClaims", err)
+		closeConnWithError(c, authError("invalid authToken"))
 		return
 	}
 	if err = assertClaims(claims); err != nil {
-		fmt.Println(err.Error())
+		closeConnWithError(c, authError(err.Error()))
 		return
 	}
-	session := &pionSession{mu: sync.Mutex{}, websocket: c, claims: claims}
+	if pionRoom.IsBanned(claims.ApiKeyID, claims.Room, c.RemoteAddr().String()) {
+		closeConnWithError(c, userError("you have been banned from this room"))
+		return
+	}
+	if pionRoom.IsLocked(claims.ApiKeyID, claims.Room) && !hasPermission(claims, permissionOp) {
+		closeConnWithError(c, userError("this room is locked"))
+		return
+	}
+	session := &pionSession{mu: sync.Mutex{}, websocket: c, claims: claims, userMessageLimiter: newRateLimiter(defaultUserMessageRate)}
+	session.touchPong()
 
 	defer func() {
 		if err := pionRoom.DestroySession(claims.ApiKeyID, claims.Room, claims.SessionKey); err != nil {
@@ -191,7 +242,9 @@ func HandleRootWSUpgrade(w http.ResponseWriter, r *http.Request) {
 				Str("SessionKey", claims.SessionKey).
 				Msg("Failed to close destroy session")
 		}
-		announceExit(claims.ApiKeyID, claims.Room, claims.SessionKey)
+		if !session.moderated.Load() {
+			announceExit(claims.ApiKeyID, claims.Room, claims.SessionKey)
+		}
 		if err := session.websocket.Close(); err != nil {
 			log.Error().Err(err).
 				Str("ApiKeyID", claims.ApiKeyID).
@@ -206,6 +259,10 @@ func HandleRootWSUpgrade(w http.ResponseWriter, r *http.Request) {
 		log.Error().Err(err).Msg("call to sendMembers failed")
 		return
 	}
+	if err = sendICEServers(session); err != nil {
+		log.Error().Err(err).Msg("call to sendICEServers failed")
+		return
+	}
 
 	handleWS(session)
 }
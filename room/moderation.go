@@ -0,0 +1,114 @@
+package room
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Moderated is implemented by the session values stored in a room so Kick
+// and Ban can close a victim's connection without this package depending on
+// the api package's concrete session type. WriteClose gives the client a
+// chance to see why it was disconnected; Close forces the connection down
+// regardless of whether the client acts on that close frame.
+type Moderated interface {
+	WriteClose(payload []byte) error
+	Close() error
+}
+
+type closeReason struct {
+	Reason string `json:"reason"`
+	By     string `json:"by"`
+}
+
+func closePayload(reason, by string) []byte {
+	body, _ := json.Marshal(closeReason{Reason: reason, By: by})
+	return websocket.FormatCloseMessage(websocket.CloseNormalClosure, string(body))
+}
+
+// banKey strips the ephemeral TCP source port from a RemoteAddr string so a
+// banned client can't evade the ban by simply reconnecting on a new port.
+func banKey(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+var lockedRooms sync.Map // roomKey(apiKey, room) -> struct{}
+var bannedAddrs sync.Map // roomKey(apiKey, room) -> *sync.Map (banKey(remoteAddr) -> struct{})
+
+// SetLocked locks or unlocks apiKey/room. While locked, HandleRootWSUpgrade
+// refuses new joiners that don't carry the "op" permission.
+func SetLocked(apiKey, room string, locked bool) {
+	key := roomKey(apiKey, room)
+	if locked {
+		lockedRooms.Store(key, struct{}{})
+	} else {
+		lockedRooms.Delete(key)
+	}
+}
+
+// IsLocked reports whether apiKey/room is currently locked.
+func IsLocked(apiKey, room string) bool {
+	_, locked := lockedRooms.Load(roomKey(apiKey, room))
+	return locked
+}
+
+// Kick closes sessionKey's connection in apiKey/room with a structured
+// close frame identifying by as the actor, force-closes the underlying
+// connection so a non-cooperating client can't linger, and removes the
+// session from the room.
+func Kick(apiKey, room, sessionKey, by string) error {
+	membersMap, ok := GetRoom(apiKey, room)
+	if !ok {
+		return nil
+	}
+	victim, ok := membersMap.Load(sessionKey)
+	if !ok {
+		return nil
+	}
+	membersMap.Delete(sessionKey)
+	return forceClose(victim.(Moderated), closePayload("kicked", by))
+}
+
+// Ban behaves like Kick, and additionally remembers remoteAddr so future
+// join attempts from it are refused for the lifetime of the room.
+func Ban(apiKey, room, sessionKey, remoteAddr, by string) error {
+	v, _ := bannedAddrs.LoadOrStore(roomKey(apiKey, room), &sync.Map{})
+	v.(*sync.Map).Store(banKey(remoteAddr), struct{}{})
+
+	membersMap, ok := GetRoom(apiKey, room)
+	if !ok {
+		return nil
+	}
+	victim, ok := membersMap.Load(sessionKey)
+	if !ok {
+		return nil
+	}
+	membersMap.Delete(sessionKey)
+	return forceClose(victim.(Moderated), closePayload("banned", by))
+}
+
+// forceClose writes payload as a best-effort close frame, then forces the
+// connection down regardless of whether the write succeeded.
+func forceClose(victim Moderated, payload []byte) error {
+	writeErr := victim.WriteClose(payload)
+	if closeErr := victim.Close(); closeErr != nil {
+		return closeErr
+	}
+	return writeErr
+}
+
+// IsBanned reports whether remoteAddr is banned from apiKey/room.
+func IsBanned(apiKey, room, remoteAddr string) bool {
+	v, ok := bannedAddrs.Load(roomKey(apiKey, room))
+	if !ok {
+		return false
+	}
+	_, banned := v.(*sync.Map).Load(banKey(remoteAddr))
+	return banned
+}
@@ -0,0 +1,55 @@
+package room
+
+import "testing"
+
+func TestBanSurvivesReconnectFromSamePortlessAddress(t *testing.T) {
+	const apiKey, roomName, sessionKey, by = "key1", "room1", "sess1", "admin"
+
+	StoreSession(apiKey, roomName, sessionKey, &stubModerated{})
+	if err := Ban(apiKey, roomName, sessionKey, "203.0.113.5:55555", by); err != nil {
+		t.Fatalf("Ban returned error: %v", err)
+	}
+
+	if !IsBanned(apiKey, roomName, "203.0.113.5:1234") {
+		t.Fatal("expected IsBanned to report banned for a reconnect using a different ephemeral port")
+	}
+	if IsBanned(apiKey, roomName, "203.0.113.6:55555") {
+		t.Fatal("expected IsBanned to report false for a different IP")
+	}
+}
+
+func TestKickRemovesSessionAndForcesClose(t *testing.T) {
+	const apiKey, roomName, sessionKey, by = "key2", "room2", "sess2", "admin"
+
+	victim := &stubModerated{}
+	StoreSession(apiKey, roomName, sessionKey, victim)
+
+	if err := Kick(apiKey, roomName, sessionKey, by); err != nil {
+		t.Fatalf("Kick returned error: %v", err)
+	}
+
+	if !victim.wroteClose {
+		t.Error("expected Kick to write a close frame")
+	}
+	if !victim.closed {
+		t.Error("expected Kick to force-close the connection")
+	}
+	if _, ok := GetSession(apiKey, roomName, sessionKey); ok {
+		t.Error("expected kicked session to be removed from the room")
+	}
+}
+
+type stubModerated struct {
+	wroteClose bool
+	closed     bool
+}
+
+func (s *stubModerated) WriteClose(payload []byte) error {
+	s.wroteClose = true
+	return nil
+}
+
+func (s *stubModerated) Close() error {
+	s.closed = true
+	return nil
+}
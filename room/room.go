@@ -0,0 +1,48 @@
+// Package room tracks the set of sessions joined to each room, keyed by
+// API key and room name. Rooms are created lazily the first time a session
+// joins and are never explicitly torn down; they simply become empty.
+package room
+
+import "sync"
+
+func roomKey(apiKey, room string) string {
+	return apiKey + "/" + room
+}
+
+var rooms sync.Map // roomKey(apiKey, room) -> *sync.Map (sessionKey -> session)
+
+// GetRoom returns the members map for apiKey/room, if it exists.
+func GetRoom(apiKey, room string) (*sync.Map, bool) {
+	v, ok := rooms.Load(roomKey(apiKey, room))
+	if !ok {
+		return nil, false
+	}
+	return v.(*sync.Map), true
+}
+
+// GetSession returns the session stored under sessionKey in apiKey/room.
+func GetSession(apiKey, room, sessionKey string) (interface{}, bool) {
+	membersMap, ok := GetRoom(apiKey, room)
+	if !ok {
+		return nil, false
+	}
+	return membersMap.Load(sessionKey)
+}
+
+// StoreSession adds or replaces the session for sessionKey in apiKey/room,
+// creating the room's members map on first use.
+func StoreSession(apiKey, room, sessionKey string, session interface{}) {
+	v, _ := rooms.LoadOrStore(roomKey(apiKey, room), &sync.Map{})
+	v.(*sync.Map).Store(sessionKey, session)
+}
+
+// DestroySession removes sessionKey from apiKey/room. It is a no-op if the
+// room or session is already gone.
+func DestroySession(apiKey, room, sessionKey string) error {
+	membersMap, ok := GetRoom(apiKey, room)
+	if !ok {
+		return nil
+	}
+	membersMap.Delete(sessionKey)
+	return nil
+}